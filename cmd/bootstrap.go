@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/rancher/rke/services"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	bootstrapKubeconfigFile = "kubeconfig.yaml"
+	bootstrapTokenFile      = "bootstrap-token.yaml"
+
+	bootstrapTokenIDBytes     = 3 // 6 hex chars, matching the kubeadm token-id format
+	bootstrapTokenSecretBytes = 8 // 16 hex chars, matching the kubeadm token-secret format
+
+	kubeconfigSkeletonTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://%s:6443
+    certificate-authority-data: PLACEHOLDER_CA_DATA
+contexts:
+- name: local
+  context:
+    cluster: local
+    user: local
+current-context: local
+users:
+- name: local
+  user: {}
+`
+
+	bootstrapTokenSecretTemplate = `apiVersion: v1
+kind: Secret
+metadata:
+  name: bootstrap-token-%s
+  namespace: kube-system
+type: bootstrap.kubernetes.io/token
+stringData:
+  token-id: "%s"
+  token-secret: "%s"
+  usage-bootstrap-authentication: "true"
+  usage-bootstrap-signing: "true"
+`
+)
+
+// emitBootstrapArtifacts writes a kubeconfig skeleton pointing at cluster's first control plane node and
+// a bootstrap-token.yaml Secret manifest, then appends that manifest's path to cluster.AddonsInclude so
+// it gets applied as part of the addon bootstrap. --emit-bootstrap is an optional extra on top of the
+// config command's real job of writing cluster.yml, so a missing control plane node here is logged and
+// skipped rather than failing the whole command.
+func emitBootstrapArtifacts(cluster *v3.RancherKubernetesEngineConfig) error {
+	if controlPlaneAddress, ok := firstControlPlaneAddress(cluster); ok {
+		kubeconfig := fmt.Sprintf(kubeconfigSkeletonTemplate, controlPlaneAddress)
+		if err := ioutil.WriteFile(bootstrapKubeconfigFile, []byte(kubeconfig), 0640); err != nil {
+			return err
+		}
+	} else {
+		logrus.Warnf("no control plane node found, skipping %s", bootstrapKubeconfigFile)
+	}
+
+	tokenID, tokenSecret, err := generateBootstrapToken()
+	if err != nil {
+		return err
+	}
+
+	tokenSecretManifest := fmt.Sprintf(bootstrapTokenSecretTemplate, tokenID, tokenID, tokenSecret)
+	if err := ioutil.WriteFile(bootstrapTokenFile, []byte(tokenSecretManifest), 0640); err != nil {
+		return err
+	}
+
+	cluster.AddonsInclude = append(cluster.AddonsInclude, bootstrapTokenFile)
+	return nil
+}
+
+// firstControlPlaneAddress returns the Address of the first node with the controlplane role and true, or
+// ("", false) if no such node exists yet.
+func firstControlPlaneAddress(cluster *v3.RancherKubernetesEngineConfig) (string, bool) {
+	for _, node := range cluster.Nodes {
+		for _, role := range node.Role {
+			if role == services.ControlRole {
+				return node.Address, true
+			}
+		}
+	}
+	return "", false
+}
+
+// generateBootstrapToken returns a kubeadm-style join token id and secret (6 and 16 hex characters,
+// respectively, i.e. <id>.<secret> as a single token string).
+func generateBootstrapToken() (id, secret string, err error) {
+	id, err = randomHex(bootstrapTokenIDBytes)
+	if err != nil {
+		return "", "", err
+	}
+	secret, err = randomHex(bootstrapTokenSecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+	return id, secret, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
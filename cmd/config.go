@@ -13,6 +13,8 @@ import (
 	"github.com/rancher/rke/nodeconfigproviders"
 	// Importing dockermachine nodeprovider so that it inits when config command is executed
 	_ "github.com/rancher/rke/nodeconfigproviders/dockermachine"
+	// Importing terraform nodeprovider so that it inits when config command is executed
+	_ "github.com/rancher/rke/nodeconfigproviders/terraform"
 	"github.com/rancher/rke/pki"
 	"github.com/rancher/rke/services"
 	"github.com/rancher/types/apis/management.cattle.io/v3"
@@ -59,7 +61,27 @@ func ConfigCommand() cli.Command {
 			},
 			cli.StringFlag{
 				Name:  "node-provider,N",
-				Usage: "Get node configurations from a node provider. ie. docker-machine",
+				Usage: "Get node configurations from a node provider. ie. docker-machine, terraform",
+			},
+			cli.StringFlag{
+				Name:  "answers",
+				Usage: "Path to an answers file (YAML/JSON5) to build the config from instead of prompting",
+			},
+			cli.StringFlag{
+				Name:  "input",
+				Usage: "Path to an existing cluster config to use as the base when merging in --answers",
+			},
+			cli.StringFlag{
+				Name:  "private-registry",
+				Usage: "Private registry (host[/namespace]) to rewrite system images to, for air-gapped clusters",
+			},
+			cli.StringFlag{
+				Name:  "registry-mapping",
+				Usage: "Path to a YAML file mapping source registries to per-image private-registry overrides",
+			},
+			cli.BoolFlag{
+				Name:  "emit-bootstrap",
+				Usage: "Also write a bootstrap kubeconfig and a cluster-bootstrap token addon manifest",
 			},
 		},
 	}
@@ -101,8 +123,14 @@ func writeConfig(cluster *v3.RancherKubernetesEngineConfig, configFile string, p
 }
 
 func clusterConfig(ctx *cli.Context) error {
+	privateRegistry := ctx.String("private-registry")
+	registryMapping, err := loadRegistryMapping(ctx.String("registry-mapping"))
+	if err != nil {
+		return err
+	}
+
 	if ctx.Bool("system-images") {
-		return generateSystemImagesList(ctx.String("version"), ctx.Bool("all"))
+		return generateSystemImagesList(ctx.String("version"), ctx.Bool("all"), privateRegistry, registryMapping)
 	}
 	configFile := ctx.String("name")
 	print := ctx.Bool("print")
@@ -117,6 +145,27 @@ func clusterConfig(ctx *cli.Context) error {
 		return writeConfig(&cluster, configFile, print)
 	}
 
+	// Build from an answers file instead of prompting, optionally merged over an existing config
+	if answersFile := ctx.String("answers"); answersFile != "" {
+		rkeConfig, err := buildConfigFromAnswers(answersFile, ctx.String("input"))
+		if err != nil {
+			return err
+		}
+		if privateRegistry != "" {
+			rkeConfig.SystemImages = rewriteSystemImages(rkeConfig.SystemImages, privateRegistry, registryMapping)
+			rkeConfig.PrivateRegistries = append(rkeConfig.PrivateRegistries, v3.PrivateRegistry{
+				URL:       privateRegistry,
+				IsDefault: true,
+			})
+		}
+		if ctx.Bool("emit-bootstrap") {
+			if err := emitBootstrapArtifacts(rkeConfig); err != nil {
+				logrus.Warnf("failed to emit bootstrap artifacts: %v", err)
+			}
+		}
+		return writeConfig(rkeConfig, configFile, print)
+	}
+
 	sshKeyPath, err := getConfig(reader, "Cluster Level SSH Private Key Path", "~/.ssh/id_rsa")
 	if err != nil {
 		return err
@@ -199,6 +248,14 @@ func clusterConfig(ctx *cli.Context) error {
 	}
 	cluster.SystemImages = *systemImages
 
+	if privateRegistry != "" {
+		cluster.SystemImages = rewriteSystemImages(cluster.SystemImages, privateRegistry, registryMapping)
+		cluster.PrivateRegistries = append(cluster.PrivateRegistries, v3.PrivateRegistry{
+			URL:       privateRegistry,
+			IsDefault: true,
+		})
+	}
+
 	// Get Services Config
 	serviceConfig, err := getServiceConfig(reader)
 	if err != nil {
@@ -216,6 +273,12 @@ func clusterConfig(ctx *cli.Context) error {
 		cluster.AddonsInclude = append(cluster.AddonsInclude, addonsInclude...)
 	}
 
+	if ctx.Bool("emit-bootstrap") {
+		if err := emitBootstrapArtifacts(&cluster); err != nil {
+			logrus.Warnf("failed to emit bootstrap artifacts: %v", err)
+		}
+	}
+
 	return writeConfig(&cluster, configFile, print)
 }
 
@@ -435,7 +498,7 @@ func getAddonManifests(reader *bufio.Reader) ([]string, error) {
 	return addonSlice, nil
 }
 
-func generateSystemImagesList(version string, all bool) error {
+func generateSystemImagesList(version string, all bool, privateRegistry string, mapping registryMapping) error {
 	allVersions := []string{}
 	for version := range v3.AllK8sVersions {
 		allVersions = append(allVersions, version)
@@ -443,6 +506,9 @@ func generateSystemImagesList(version string, all bool) error {
 	if all {
 		for version, rkeSystemImages := range v3.AllK8sVersions {
 			logrus.Infof("Generating images list for version [%s]:", version)
+			if privateRegistry != "" {
+				rkeSystemImages = rewriteSystemImages(rkeSystemImages, privateRegistry, mapping)
+			}
 			uniqueImages := getUniqueSystemImageList(rkeSystemImages)
 			for _, image := range uniqueImages {
 				if image == "" {
@@ -461,6 +527,9 @@ func generateSystemImagesList(version string, all bool) error {
 		return fmt.Errorf("k8s version is not supported, supported versions are: %v", allVersions)
 	}
 	logrus.Infof("Generating images list for version [%s]:", version)
+	if privateRegistry != "" {
+		rkeSystemImages = rewriteSystemImages(rkeSystemImages, privateRegistry, mapping)
+	}
 	uniqueImages := getUniqueSystemImageList(rkeSystemImages)
 	for _, image := range uniqueImages {
 		if image == "" {
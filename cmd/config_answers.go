@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+	"gopkg.in/yaml.v2"
+)
+
+// appendableAnswerKeys lists the config keys that are appended to rather than replaced when an answers
+// file is merged over a base config, so environment-specific answers can layer node lists and addons on
+// top of a shared base instead of clobbering them.
+var appendableAnswerKeys = map[string]bool{
+	"nodes":          true,
+	"addons_include": true,
+}
+
+// buildConfigFromAnswers builds a cluster config straight from an answers file, with no prompting. When
+// inputFile is non-empty, it is read as the base config and the answers are merged over it; otherwise
+// the base is today's interactive defaults.
+func buildConfigFromAnswers(answersFile, inputFile string) (*v3.RancherKubernetesEngineConfig, error) {
+	answers, err := readConfigMap(answersFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read answers file [%s]: %v", answersFile, err)
+	}
+
+	base, err := toConfigMap(defaultClusterConfig())
+	if err != nil {
+		return nil, err
+	}
+	if inputFile != "" {
+		base, err = readConfigMap(inputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input config [%s]: %v", inputFile, err)
+		}
+	}
+
+	merged := mergeConfigMaps(base, answers)
+
+	rkeConfig := &v3.RancherKubernetesEngineConfig{}
+	if err := remarshal(merged, rkeConfig); err != nil {
+		return nil, fmt.Errorf("failed to apply merged answers: %v", err)
+	}
+	return rkeConfig, nil
+}
+
+// mergeConfigMaps merges override over base. A key present in override always wins, even when its value
+// is a zero value such as false or 0 — unlike a struct-level merge, a map only has a key if it was
+// actually set in the file, so "explicitly false" and "not mentioned" stay distinguishable. Nested
+// objects are merged recursively; appendableAnswerKeys are concatenated instead of replaced.
+func mergeConfigMaps(base, override map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, overrideValue := range override {
+		baseValue, exists := merged[k]
+		if !exists {
+			merged[k] = overrideValue
+			continue
+		}
+
+		if overrideMap, ok := overrideValue.(map[string]interface{}); ok {
+			if baseMap, ok := baseValue.(map[string]interface{}); ok {
+				merged[k] = mergeConfigMaps(baseMap, overrideMap)
+				continue
+			}
+		}
+
+		if appendableAnswerKeys[k] {
+			if overrideSlice, ok := overrideValue.([]interface{}); ok {
+				if baseSlice, ok := baseValue.([]interface{}); ok {
+					merged[k] = append(append([]interface{}{}, baseSlice...), overrideSlice...)
+					continue
+				}
+			}
+		}
+
+		merged[k] = overrideValue
+	}
+
+	return merged
+}
+
+// readConfigMap reads a cluster config file as a generic map, from YAML, JSON or JSON5 picked by file
+// extension, so callers can tell an explicitly-set zero value apart from an absent key. JSON5 is
+// supported because answer files are typically hand-edited and benefit from comments and trailing commas.
+func readConfigMap(path string) (map[string]interface{}, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".json5" {
+		var decoded map[string]interface{}
+		if err := json5.Unmarshal(data, &decoded); err != nil {
+			return nil, err
+		}
+		return decoded, nil
+	}
+
+	var decoded map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return normalizeYAMLMap(decoded), nil
+}
+
+// toConfigMap round-trips a typed cluster config through YAML to get the generic map representation
+// mergeConfigMaps operates on.
+func toConfigMap(rkeConfig *v3.RancherKubernetesEngineConfig) (map[string]interface{}, error) {
+	data, err := yaml.Marshal(rkeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return normalizeYAMLMap(decoded), nil
+}
+
+// remarshal round-trips a generic config map back into a typed cluster config via YAML.
+func remarshal(config map[string]interface{}, out *v3.RancherKubernetesEngineConfig) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, out)
+}
+
+// normalizeYAMLMap converts the map[interface{}]interface{} that yaml.v2 produces for nested objects
+// into map[string]interface{}, recursively, so it matches what JSON5/JSON decoding already produces and
+// mergeConfigMaps only has one shape to deal with.
+func normalizeYAMLMap(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalizeYAMLMap(val)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, item := range v {
+			normalized[i] = normalizeYAMLMap(item)
+		}
+		return normalized
+	default:
+		return value
+	}
+}
+
+// defaultClusterConfig mirrors the defaults the interactive wizard falls back to when a prompt is left
+// blank, so answer files only need to set the fields they actually want to change.
+func defaultClusterConfig() *v3.RancherKubernetesEngineConfig {
+	rkeConfig := &v3.RancherKubernetesEngineConfig{}
+
+	rkeConfig.SSHKeyPath = "~/.ssh/id_rsa"
+	rkeConfig.Network = v3.NetworkConfig{Plugin: cluster.DefaultNetworkPlugin}
+	rkeConfig.Authentication = v3.AuthnConfig{Strategy: cluster.DefaultAuthStrategy}
+	rkeConfig.Authorization = v3.AuthzConfig{Mode: cluster.DefaultAuthorizationMode}
+	rkeConfig.SystemImages = v3.K8sVersionToRKESystemImages[cluster.DefaultK8sVersion]
+
+	rkeConfig.Services = v3.RKEConfigServices{
+		Etcd:           v3.ETCDService{},
+		KubeAPI:        v3.KubeAPIService{ServiceClusterIPRange: cluster.DefaultServiceClusterIPRange},
+		KubeController: v3.KubeControllerService{ServiceClusterIPRange: cluster.DefaultServiceClusterIPRange, ClusterCIDR: cluster.DefaultClusterCIDR},
+		Scheduler:      v3.SchedulerService{},
+		Kubelet:        v3.KubeletService{ClusterDomain: cluster.DefaultClusterDomain, ClusterDNSServer: cluster.DefaultClusterDNSService},
+		Kubeproxy:      v3.KubeproxyService{},
+	}
+
+	return rkeConfig
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultRegistry is the implicit registry for image references with no host component, matching how
+// Docker itself resolves a bare "repo/image" reference.
+const defaultRegistry = "docker.io"
+
+// registryMapping maps a source registry, as it appears in an image reference (e.g. "quay.io"), to the
+// registry (and optional namespace) it should be rewritten to. Sources not present in the mapping fall
+// back to the --private-registry value.
+type registryMapping map[string]string
+
+// loadRegistryMapping reads a --registry-mapping file. An empty path is valid and yields an empty mapping.
+func loadRegistryMapping(path string) (registryMapping, error) {
+	mapping := registryMapping{}
+	if path == "" {
+		return mapping, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &mapping); err != nil {
+		return nil, err
+	}
+	return mapping, nil
+}
+
+// rewriteImage rewrites the registry of a single image reference to privateRegistry, or to mapping's
+// entry for that image's source registry if one exists, while preserving the repository path and any
+// tag or digest.
+func rewriteImage(image, privateRegistry string, mapping registryMapping) string {
+	if image == "" || privateRegistry == "" {
+		return image
+	}
+
+	registry, repository, suffix := splitImageReference(image)
+
+	target, ok := mapping[registry]
+	if !ok {
+		target = privateRegistry
+	}
+
+	return target + "/" + repository + suffix
+}
+
+// splitImageReference splits a docker image reference into its source registry (defaultRegistry if the
+// reference doesn't name one), its repository path, and its tag/digest suffix (including the leading
+// ":" or "@", or "" if the reference is untagged).
+func splitImageReference(image string) (registry, repository, suffix string) {
+	name := image
+
+	if at := strings.Index(name, "@"); at != -1 {
+		suffix = name[at:]
+		name = name[:at]
+	} else if colon := strings.LastIndex(name, ":"); colon != -1 && !strings.Contains(name[colon:], "/") {
+		suffix = name[colon:]
+		name = name[:colon]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1], suffix
+	}
+
+	return defaultRegistry, name, suffix
+}
+
+// rewriteSystemImages returns a copy of images with every field run through rewriteImage.
+func rewriteSystemImages(images v3.RKESystemImages, privateRegistry string, mapping registryMapping) v3.RKESystemImages {
+	rewritten := images
+	val := reflect.ValueOf(&rewritten).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		field.SetString(rewriteImage(field.String(), privateRegistry, mapping))
+	}
+	return rewritten
+}
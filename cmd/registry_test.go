@@ -0,0 +1,110 @@
+package cmd
+
+import "testing"
+
+func TestRewriteImage(t *testing.T) {
+	tests := []struct {
+		name            string
+		image           string
+		privateRegistry string
+		mapping         registryMapping
+		want            string
+	}{
+		{
+			name:            "docker.io image with no registry",
+			image:           "rancher/rke-tools:v0.1.0",
+			privateRegistry: "mirror.example.com",
+			want:            "mirror.example.com/rancher/rke-tools:v0.1.0",
+		},
+		{
+			name:            "digest pinned image",
+			image:           "rancher/hyperkube@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcd",
+			privateRegistry: "mirror.example.com",
+			want:            "mirror.example.com/rancher/hyperkube@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcd",
+		},
+		{
+			name:            "image with an explicit non-default registry",
+			image:           "gcr.io/google-containers/pause:3.1",
+			privateRegistry: "mirror.example.com",
+			want:            "mirror.example.com/google-containers/pause:3.1",
+		},
+		{
+			name:            "mapping overrides privateRegistry for a matched source",
+			image:           "quay.io/coreos/flannel:v0.10.0",
+			privateRegistry: "mirror.example.com",
+			mapping:         registryMapping{"quay.io": "mirror.example.com/quay-mirror"},
+			want:            "mirror.example.com/quay-mirror/coreos/flannel:v0.10.0",
+		},
+		{
+			name:            "k8s.gcr.io image routed by mapping, digest preserved",
+			image:           "k8s.gcr.io/pause@sha256:deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			privateRegistry: "mirror.example.com",
+			mapping:         registryMapping{"k8s.gcr.io": "mirror.example.com/k8s-mirror"},
+			want:            "mirror.example.com/k8s-mirror/pause@sha256:deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+		},
+		{
+			name:            "no private registry leaves the image untouched",
+			image:           "rancher/rke-tools:v0.1.0",
+			privateRegistry: "",
+			want:            "rancher/rke-tools:v0.1.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rewriteImage(tt.image, tt.privateRegistry, tt.mapping)
+			if got != tt.want {
+				t.Errorf("rewriteImage(%q, %q, %v) = %q, want %q", tt.image, tt.privateRegistry, tt.mapping, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitImageReference(t *testing.T) {
+	tests := []struct {
+		name           string
+		image          string
+		wantRegistry   string
+		wantRepository string
+		wantSuffix     string
+	}{
+		{
+			name:           "implicit docker.io registry",
+			image:          "rancher/rke-tools:v0.1.0",
+			wantRegistry:   defaultRegistry,
+			wantRepository: "rancher/rke-tools",
+			wantSuffix:     ":v0.1.0",
+		},
+		{
+			name:           "digest pinned, no explicit registry",
+			image:          "rancher/hyperkube@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcd",
+			wantRegistry:   defaultRegistry,
+			wantRepository: "rancher/hyperkube",
+			wantSuffix:     "@sha256:1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcd",
+		},
+		{
+			name:           "explicit registry with port",
+			image:          "localhost:5000/rancher/rke-tools:v0.1.0",
+			wantRegistry:   "localhost:5000",
+			wantRepository: "rancher/rke-tools",
+			wantSuffix:     ":v0.1.0",
+		},
+		{
+			name:           "untagged image",
+			image:          "gcr.io/google-containers/pause",
+			wantRegistry:   "gcr.io",
+			wantRepository: "google-containers/pause",
+			wantSuffix:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, repository, suffix := splitImageReference(tt.image)
+			if registry != tt.wantRegistry || repository != tt.wantRepository || suffix != tt.wantSuffix {
+				t.Errorf("splitImageReference(%q) = (%q, %q, %q), want (%q, %q, %q)",
+					tt.image, registry, repository, suffix, tt.wantRegistry, tt.wantRepository, tt.wantSuffix)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package nodeconfigproviders
+
+import (
+	"bufio"
+	"fmt"
+
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+// NodeConfigProvider is an alternate source of cluster nodes for the interactive per-host prompts in
+// `rke config`, selected with -N/--node-provider.
+type NodeConfigProvider interface {
+	// GetNodesFromConfig prompts the user, via reader, for whatever is needed to locate and filter the
+	// provider's machines, and returns the subset the user selected.
+	GetNodesFromConfig(reader *bufio.Reader) ([]interface{}, error)
+	// ReadNodeConfigurations converts the machines returned by GetNodesFromConfig into RKEConfigNode
+	// entries ready to append to the cluster config.
+	ReadNodeConfigurations(machines []interface{}) ([]v3.RKEConfigNode, error)
+}
+
+var providers = map[string]NodeConfigProvider{}
+
+// RegisterNodeConfigProvider makes a provider available under name. Providers call this from their own
+// package's init(), which must be blank-imported somewhere for it to ever run.
+func RegisterNodeConfigProvider(name string, provider NodeConfigProvider) {
+	providers[name] = provider
+}
+
+// GetNodeProvider looks up a provider previously registered with RegisterNodeConfigProvider.
+func GetNodeProvider(name string) (NodeConfigProvider, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("node config provider [%s] is not registered", name)
+	}
+	return provider, nil
+}
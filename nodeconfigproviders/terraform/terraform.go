@@ -0,0 +1,441 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/rancher/rke/cluster"
+	"github.com/rancher/rke/nodeconfigproviders"
+	"github.com/rancher/rke/services"
+	"github.com/rancher/types/apis/management.cattle.io/v3"
+)
+
+const providerName = "terraform"
+
+// supportedResourceTypes lists the Terraform resource types this provider knows how to turn into nodes.
+var supportedResourceTypes = map[string]bool{
+	"aws_instance":                  true,
+	"azurerm_linux_virtual_machine": true,
+	"google_compute_instance":       true,
+	"hcloud_server":                 true,
+	"vsphere_virtual_machine":       true,
+}
+
+func init() {
+	nodeconfigproviders.RegisterNodeConfigProvider(providerName, &Provider{})
+}
+
+// Provider reads RKEConfigNode entries out of a Terraform state file, local or remote, so clusters can
+// be bootstrapped straight from infrastructure already provisioned with `terraform apply`.
+type Provider struct {
+	outputs map[string]tfOutput
+}
+
+// instance pairs a selected Terraform resource instance with the role(s) the user assigned it.
+type instance struct {
+	resourceType string
+	resourceName string
+	attributes   map[string]interface{}
+	roles        []string
+}
+
+// tfState is the subset of the Terraform state file format (version 4) this provider needs.
+type tfState struct {
+	Outputs   map[string]tfOutput `json:"outputs"`
+	Resources []tfResource        `json:"resources"`
+}
+
+type tfOutput struct {
+	Value interface{} `json:"value"`
+}
+
+type tfResource struct {
+	Type      string       `json:"type"`
+	Name      string       `json:"name"`
+	Module    string       `json:"module"`
+	Instances []tfInstance `json:"instances"`
+}
+
+type tfInstance struct {
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// GetNodesFromConfig prompts for the state file location and filters, then lets the user pick which
+// resource instances become nodes and what role(s) each one takes.
+func (p *Provider) GetNodesFromConfig(reader *bufio.Reader) ([]interface{}, error) {
+	source, err := prompt(reader, "Terraform state file path or backend URL", "terraform.tfstate")
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := loadState(source)
+	if err != nil {
+		return nil, err
+	}
+	p.outputs = state.Outputs
+
+	resourceFilter, err := prompt(reader, "Filter by resource type (blank for all)", "")
+	if err != nil {
+		return nil, err
+	}
+
+	moduleFilter, err := prompt(reader, "Filter by module path (blank for all)", "")
+	if err != nil {
+		return nil, err
+	}
+
+	tagFilter, err := prompt(reader, "Filter by tag (key=value, blank for none)", "")
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := filterResources(state.Resources, resourceFilter, moduleFilter, tagFilter)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no matching Terraform resources found in state [%s]", source)
+	}
+
+	useTagMapping, err := prompt(reader, "Assign roles from the \"rke_role\" tag instead of per instance (y/n)?", "n")
+	if err != nil {
+		return nil, err
+	}
+
+	var machines []interface{}
+	for _, candidate := range candidates {
+		for instanceIndex, candidateInstance := range candidate.Instances {
+			label := fmt.Sprintf("%s.%s[%d]", candidate.Type, candidate.Name, instanceIndex)
+
+			include, err := prompt(reader, fmt.Sprintf("Include instance (%s) (y/n)?", label), "y")
+			if err != nil {
+				return nil, err
+			}
+			if !isYes(include) {
+				continue
+			}
+
+			attributes := candidateInstance.Attributes
+
+			var roles []string
+			if isYes(useTagMapping) {
+				roles = rolesFromTags(attributes)
+			} else {
+				roles, err = promptRoles(reader, label)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			machines = append(machines, &instance{
+				resourceType: candidate.Type,
+				resourceName: candidate.Name,
+				attributes:   attributes,
+				roles:        roles,
+			})
+		}
+	}
+
+	return machines, nil
+}
+
+// ReadNodeConfigurations turns the instances selected by GetNodesFromConfig into RKEConfigNode entries.
+func (p *Provider) ReadNodeConfigurations(machines []interface{}) ([]v3.RKEConfigNode, error) {
+	nodes := make([]v3.RKEConfigNode, 0, len(machines))
+	for _, machine := range machines {
+		inst, ok := machine.(*instance)
+		if !ok {
+			return nil, fmt.Errorf("unexpected node type %T from terraform provider", machine)
+		}
+
+		user := stringAttr(inst.attributes, "user", "ssh_user")
+		if user == "" {
+			user = outputString(p.outputs, "ssh_user")
+		}
+		if user == "" {
+			user = "ubuntu"
+		}
+
+		sshKeyPath := stringAttr(inst.attributes, "ssh_key_path")
+		if sshKeyPath == "" {
+			sshKeyPath = outputString(p.outputs, "ssh_key_path")
+		}
+		if sshKeyPath == "" {
+			sshKeyPath = "~/.ssh/id_rsa"
+		}
+
+		port := stringAttr(inst.attributes, "ssh_port")
+		if port == "" {
+			port = cluster.DefaultSSHPort
+		}
+
+		dockerSocket := stringAttr(inst.attributes, "docker_socket_path")
+		if dockerSocket == "" {
+			dockerSocket = cluster.DefaultDockerSockPath
+		}
+
+		address, internalAddress, hostname := resourceAddresses(inst.resourceType, inst.attributes)
+
+		nodes = append(nodes, v3.RKEConfigNode{
+			Address:          address,
+			InternalAddress:  internalAddress,
+			HostnameOverride: hostname,
+			User:             user,
+			SSHKeyPath:       sshKeyPath,
+			Port:             port,
+			DockerSocket:     dockerSocket,
+			Role:             inst.roles,
+		})
+	}
+	return nodes, nil
+}
+
+// resourceAddresses resolves a node's address, internal address and hostname from a Terraform instance's
+// attributes, following each supported resource type's own attribute names and nesting.
+func resourceAddresses(resourceType string, attributes map[string]interface{}) (address, internalAddress, hostname string) {
+	switch resourceType {
+	case "aws_instance":
+		return stringAttr(attributes, "public_ip"),
+			stringAttr(attributes, "private_ip"),
+			firstNonEmpty(tagValue(attributes, "Name"), stringAttr(attributes, "id"))
+	case "azurerm_linux_virtual_machine":
+		return stringAttr(attributes, "public_ip_address"),
+			stringAttr(attributes, "private_ip_address"),
+			firstNonEmpty(tagValue(attributes, "Name"), stringAttr(attributes, "computer_name"), stringAttr(attributes, "name"))
+	case "google_compute_instance":
+		address, internalAddress = googleComputeAddresses(attributes)
+		return address, internalAddress, stringAttr(attributes, "name")
+	case "hcloud_server":
+		return stringAttr(attributes, "ipv4_address"), stringAttr(attributes, "ipv4_address"), stringAttr(attributes, "name")
+	case "vsphere_virtual_machine":
+		return stringAttr(attributes, "default_ip_address"), stringAttr(attributes, "default_ip_address"), stringAttr(attributes, "name")
+	default:
+		return "", "", ""
+	}
+}
+
+// googleComputeAddresses reads the external (NAT) and internal IP of a google_compute_instance out of
+// its nested network_interface[0] block, since unlike the other supported resource types these aren't
+// top-level attributes.
+func googleComputeAddresses(attributes map[string]interface{}) (address, internalAddress string) {
+	interfaces, _ := attributes["network_interface"].([]interface{})
+	if len(interfaces) == 0 {
+		return "", ""
+	}
+	iface, _ := interfaces[0].(map[string]interface{})
+	if iface == nil {
+		return "", ""
+	}
+
+	internalAddress = nestedString(iface, "network_ip")
+
+	accessConfigs, _ := iface["access_config"].([]interface{})
+	if len(accessConfigs) > 0 {
+		if ac, ok := accessConfigs[0].(map[string]interface{}); ok {
+			address = nestedString(ac, "nat_ip")
+		}
+	}
+
+	return address, internalAddress
+}
+
+func nestedString(attributes map[string]interface{}, key string) string {
+	value, ok := attributes[key]
+	if !ok {
+		return ""
+	}
+	if s := fmt.Sprintf("%v", value); s != "" && s != "<nil>" {
+		return s
+	}
+	return ""
+}
+
+// tagValue reads a single key out of a resource's nested "tags" map (aws_instance, azurerm_linux_virtual_machine).
+func tagValue(attributes map[string]interface{}, key string) string {
+	tags, _ := attributes["tags"].(map[string]interface{})
+	if tags == nil {
+		return ""
+	}
+	return nestedString(tags, key)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// loadState fetches a Terraform state file from a local path or, if source is an http(s) URL, a remote
+// backend such as an HTTP or Terraform Cloud state endpoint.
+func loadState(source string) (*tfState, error) {
+	var data []byte
+	var err error
+
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, httpErr := http.Get(source)
+		if httpErr != nil {
+			return nil, httpErr
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch terraform state from [%s]: status %d", source, resp.StatusCode)
+		}
+		data, err = ioutil.ReadAll(resp.Body)
+	} else {
+		data, err = ioutil.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state := &tfState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse terraform state [%s]: %v", source, err)
+	}
+	return state, nil
+}
+
+// filterResources narrows resources down to the supported types, optionally further restricted by an
+// exact resource type, a module path substring, and a "key=value" (or bare "key") tag match.
+func filterResources(resources []tfResource, resourceType, module, tag string) []tfResource {
+	var tagKey, tagValue string
+	if tag != "" {
+		parts := strings.SplitN(tag, "=", 2)
+		tagKey = parts[0]
+		if len(parts) == 2 {
+			tagValue = parts[1]
+		}
+	}
+
+	var matched []tfResource
+	for _, resource := range resources {
+		if !supportedResourceTypes[resource.Type] {
+			continue
+		}
+		if resourceType != "" && resource.Type != resourceType {
+			continue
+		}
+		if module != "" && !strings.Contains(resource.Module, module) {
+			continue
+		}
+		if len(resource.Instances) == 0 {
+			continue
+		}
+		if tagKey != "" && !hasTag(resource.Instances[0].Attributes, tagKey, tagValue) {
+			continue
+		}
+		matched = append(matched, resource)
+	}
+	return matched
+}
+
+func hasTag(attributes map[string]interface{}, key, value string) bool {
+	tags, _ := attributes["tags"].(map[string]interface{})
+	v, ok := tags[key]
+	if !ok {
+		return false
+	}
+	if value == "" {
+		return true
+	}
+	return fmt.Sprintf("%v", v) == value
+}
+
+// rolesFromTags maps the comma-separated "rke_role" tag (e.g. "controlplane,etcd") to RKE role names.
+func rolesFromTags(attributes map[string]interface{}) []string {
+	tags, _ := attributes["tags"].(map[string]interface{})
+	raw, ok := tags["rke_role"]
+	if !ok {
+		return nil
+	}
+
+	var roles []string
+	for _, role := range strings.Split(fmt.Sprintf("%v", raw), ",") {
+		switch strings.TrimSpace(role) {
+		case "controlplane":
+			roles = append(roles, services.ControlRole)
+		case "worker":
+			roles = append(roles, services.WorkerRole)
+		case "etcd":
+			roles = append(roles, services.ETCDRole)
+		}
+	}
+	return roles
+}
+
+func promptRoles(reader *bufio.Reader, label string) ([]string, error) {
+	var roles []string
+
+	isControl, err := prompt(reader, fmt.Sprintf("Is instance (%s) a Control Plane host (y/n)?", label), "y")
+	if err != nil {
+		return nil, err
+	}
+	if isYes(isControl) {
+		roles = append(roles, services.ControlRole)
+	}
+
+	isWorker, err := prompt(reader, fmt.Sprintf("Is instance (%s) a Worker host (y/n)?", label), "n")
+	if err != nil {
+		return nil, err
+	}
+	if isYes(isWorker) {
+		roles = append(roles, services.WorkerRole)
+	}
+
+	isEtcd, err := prompt(reader, fmt.Sprintf("Is instance (%s) an etcd host (y/n)?", label), "n")
+	if err != nil {
+		return nil, err
+	}
+	if isYes(isEtcd) {
+		roles = append(roles, services.ETCDRole)
+	}
+
+	return roles, nil
+}
+
+func stringAttr(attributes map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := attributes[key]; ok {
+			if s := fmt.Sprintf("%v", value); s != "" && s != "<nil>" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func outputString(outputs map[string]tfOutput, key string) string {
+	output, ok := outputs[key]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%v", output.Value)
+}
+
+func isYes(answer string) bool {
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// prompt mirrors cmd.getConfig's prompt format so the terraform provider's prompts look identical to
+// the rest of `rke config`.
+func prompt(reader *bufio.Reader, text, def string) (string, error) {
+	if def == "" {
+		fmt.Printf("[+] %s [%s]: ", text, "none")
+	} else {
+		fmt.Printf("[+] %s [%s]: ", text, def)
+	}
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	input = strings.TrimSpace(input)
+	if input != "" {
+		return input, nil
+	}
+	return def, nil
+}